@@ -0,0 +1,127 @@
+package createRecurringReservation
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/reservation"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	PlaceID int    `json:"place_id" validate:"required"`
+	CronStr string `json:"cron_str,omitempty"`
+	RRule   string `json:"rrule" validate:"required"`
+	Enabled bool   `json:"enabled"`
+	Until   string `json:"until,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	PolicyID int `json:"policy_id"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.createRecurringReservation.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Получаем userID из токена авторизации
+		tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
+		userID, ok := tempUserID["user_id"]
+		if !ok {
+			log.Error("no user id in token claims")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no user id in token claims"))
+			return
+		}
+
+		var req Request
+
+		// Декодируем json запроса
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			// Такую ошибку встретим, если получили запрос с пустым телом.
+			// Обработаем её отдельно
+			log.Error("request body is empty")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		// Валидация обязательных полей запроса
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		if _, err := reservation.ParseRRule(req.RRule); err != nil {
+			log.Error("invalid rrule", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("invalid rrule"))
+			return
+		}
+
+		var until time.Time
+		if req.Until != "" {
+			until, err = time.Parse(time.RFC3339, req.Until)
+			if err != nil {
+				log.Error("invalid until", sl.Err(err))
+				w.WriteHeader(400)
+				render.JSON(w, r, resp.Error("invalid until"))
+				return
+			}
+		}
+
+		policy := reservation.ReservationPolicy{
+			PlaceID: req.PlaceID,
+			UserID:  userID,
+			CronStr: req.CronStr,
+			RRule:   req.RRule,
+			Enabled: req.Enabled,
+			Until:   until,
+		}
+
+		if err := policy.InsertReservationPolicy(storage); err != nil {
+			log.Error("failed to create recurring reservation", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to create recurring reservation"))
+			return
+		}
+
+		log.Info("recurring reservation policy created", slog.Int("policy_id", policy.PolicyID))
+
+		responseOK(w, r, policy.PolicyID)
+	}
+}
+
+func responseOK(w http.ResponseWriter, r *http.Request, policyID int) {
+	render.JSON(w, r, Response{
+		Response: resp.OK(),
+		PolicyID: policyID,
+	})
+}
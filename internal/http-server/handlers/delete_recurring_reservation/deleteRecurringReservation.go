@@ -0,0 +1,72 @@
+package deleteRecurringReservation
+
+import (
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/reservation"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.deleteRecurringReservation.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Получаем userID из токена авторизации
+		tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
+		userID, ok := tempUserID["user_id"]
+		if !ok {
+			log.Error("no user id in token claims")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no user id in token claims"))
+			return
+		}
+
+		policyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid policy id", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("invalid policy id"))
+			return
+		}
+
+		var policy reservation.ReservationPolicy
+		policy.PolicyID = policyID
+		if err := policy.GetReservationPolicyByID(storage); err != nil {
+			log.Error("failed to get recurring reservation", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to delete recurring reservation"))
+			return
+		}
+
+		if policy.UserID != userID {
+			log.Error("policy belongs to another user")
+			w.WriteHeader(403)
+			render.JSON(w, r, resp.Error("access was denied"))
+			return
+		}
+
+		if err := policy.DeleteReservationPolicy(storage); err != nil {
+			log.Error("failed to delete recurring reservation", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to delete recurring reservation"))
+			return
+		}
+
+		log.Info("recurring reservation policy deleted")
+
+		render.JSON(w, r, resp.OK())
+	}
+}
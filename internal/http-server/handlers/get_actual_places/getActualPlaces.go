@@ -0,0 +1,76 @@
+package get_actual_places
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"portal/internal/lib/api/pagination"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/reservation"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Items []reservation.ActualPlace `json:"items"`
+}
+
+// New отдаёт список мест на интервал [start, finish] с отметкой is_available,
+// с опциональной фильтрацией по доступности и имени места (?is_available=,
+// ?place_name=) - в отличие от get_reservation_list, который отдаёт полный
+// список без фильтров.
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.getActualPlaces.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		start, err := time.Parse(time.RFC3339, r.URL.Query().Get("start"))
+		if err != nil {
+			log.Error("invalid start", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("invalid start"))
+			return
+		}
+
+		finish, err := time.Parse(time.RFC3339, r.URL.Query().Get("finish"))
+		if err != nil {
+			log.Error("invalid finish", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("invalid finish"))
+			return
+		}
+
+		opts, err := pagination.Parse(r.URL.Query(), nil)
+		if err != nil {
+			log.Error("invalid pagination options", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("invalid pagination options"))
+			return
+		}
+
+		var ap reservation.ActualPlace
+		items, err := ap.GetActualPlaces(storage, "", start, finish, opts)
+		if err != nil {
+			log.Error("failed to get actual places", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get actual places"))
+			return
+		}
+
+		log.Info("actual places gotten", slog.Int("count", len(items)))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Items:    items,
+		})
+	}
+}
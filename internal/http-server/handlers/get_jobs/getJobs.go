@@ -0,0 +1,95 @@
+package getJobs
+
+import (
+	"log/slog"
+	"net/http"
+	"portal/internal/jobs"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres"
+	"portal/internal/structs/roles"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// defaultLimit и maxLimit ограничивают размер страницы, запрошенной клиентом.
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+type Response struct {
+	resp.Response
+	Jobs  []jobs.Job `json:"jobs"`
+	Total int        `json:"total"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.getJobs.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Определяем разрешенные роли
+		allowedRoles := []int{roles.SuperAdmin}
+
+		// Получаем user role из токена авторизации
+		role := r.Context().Value(oauth.ScopeContext).(int)
+		if role == 0 {
+			log.Error("no user role in token")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no user role in token"))
+			return
+		}
+
+		// Проверяем доступно ли действие для роли текущего пользователя
+		if !slices.Contains(allowedRoles, role) {
+			log.Error("access was denied")
+			w.WriteHeader(403)
+			render.JSON(w, r, resp.Error("access was denied"))
+			return
+		}
+
+		query := r.URL.Query()
+
+		limit := defaultLimit
+		if raw := query.Get("limit"); raw != "" {
+			if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+				limit = parsed
+			}
+		}
+		if limit > maxLimit {
+			limit = maxLimit
+		}
+
+		offset := 0
+		if raw := query.Get("offset"); raw != "" {
+			if parsed, err := strconv.Atoi(raw); err == nil && parsed >= 0 {
+				offset = parsed
+			}
+		}
+
+		jobList, total, err := jobs.GetJobs(storage, query.Get("status"), query.Get("type"), limit, offset)
+		if err != nil {
+			log.Error("failed to get jobs", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get jobs"))
+			return
+		}
+
+		log.Info("jobs gotten", slog.Int("count", len(jobList)))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Jobs:     jobList,
+			Total:    total,
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package getUserReservations
+
+import (
+	"log/slog"
+	"net/http"
+	"portal/internal/lib/api/pagination"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/reservation"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Items  []reservation.Reservation `json:"items"`
+	Total  int                       `json:"total"`
+	Limit  int                       `json:"limit"`
+	Offset int                       `json:"offset"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.getUserReservations.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Получаем userID из токена авторизации
+		tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
+		userID, ok := tempUserID["user_id"]
+		if !ok {
+			log.Error("no user id in token claims")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no user id in token claims"))
+			return
+		}
+
+		opts, err := pagination.Parse(r.URL.Query(), reservation.ReservationSortColumns)
+		if err != nil {
+			log.Error("invalid pagination options", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("invalid pagination options"))
+			return
+		}
+
+		var rsv reservation.Reservation
+		items, total, err := rsv.GetReservationsByUserID(storage, userID, opts)
+		if err != nil {
+			log.Error("failed to get reservations", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to get reservations"))
+			return
+		}
+
+		log.Info("reservations gotten", slog.Int("count", len(items)))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Items:    items,
+			Total:    total,
+			Limit:    opts.Limit,
+			Offset:   opts.Offset,
+		})
+	}
+}
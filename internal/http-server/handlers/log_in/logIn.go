@@ -7,10 +7,12 @@ import (
 	"log/slog"
 	"net/http"
 	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/auth/sessions"
 	"portal/internal/lib/jwt"
 	"portal/internal/lib/logger/sl"
 	"portal/internal/storage/postgres"
 	"portal/internal/storage/postgres/entities"
+	"portal/internal/storage/postgres/entities/session"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/jwtauth/v5"
@@ -25,7 +27,8 @@ type Request struct {
 
 type Response struct {
 	resp.Response
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 func New(log *slog.Logger, storage *postgres.Storage, tokenAuth *jwtauth.JWTAuth) http.HandlerFunc {
@@ -83,14 +86,50 @@ func New(log *slog.Logger, storage *postgres.Storage, tokenAuth *jwtauth.JWTAuth
 			return
 		}
 
-		token, _ := jwt.New(tokenAuth)
-		responseOK(w, r, token)
+		// Заводим серверную сессию с refresh-токеном, чтобы выданный access-токен
+		// можно было отозвать до истечения его срока
+		sessionID, err := sessions.NewSessionID()
+		if err != nil {
+			log.Error("failed to generate session id", sl.Err(err))
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("failed to log in"))
+			return
+		}
+
+		refreshToken, err := sessions.NewRefreshToken()
+		if err != nil {
+			log.Error("failed to generate refresh token", sl.Err(err))
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("failed to log in"))
+			return
+		}
+
+		// Claim "jti" равен session_id, чтобы middleware oauth мог отклонить токен по
+		// sessions.RevokedJTI ещё до истечения его exp
+		token, _ := jwt.New(tokenAuth, map[string]interface{}{"jti": sessionID})
+
+		s := session.Session{
+			SessionID:   sessionID,
+			UserID:      u.UserID,
+			RefreshHash: sessions.HashRefreshToken(refreshToken),
+			UserAgent:   r.UserAgent(),
+			IP:          r.RemoteAddr,
+		}
+		if err := s.InsertSession(storage); err != nil {
+			log.Error("failed to create session", sl.Err(err))
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("failed to log in"))
+			return
+		}
+
+		responseOK(w, r, token, refreshToken)
 	}
 }
 
-func responseOK(w http.ResponseWriter, r *http.Request, token string) {
+func responseOK(w http.ResponseWriter, r *http.Request, token, refreshToken string) {
 	render.JSON(w, r, Response{
-		Response: resp.OK(),
-		Token:    token,
+		Response:     resp.OK(),
+		Token:        token,
+		RefreshToken: refreshToken,
 	})
 }
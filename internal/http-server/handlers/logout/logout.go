@@ -0,0 +1,104 @@
+package logout
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/auth/sessions"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/session"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	SessionID string `json:"session_id" validate:"required"`
+}
+
+type Response struct {
+	resp.Response
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.logout.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Получаем userID из токена авторизации
+		tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
+		userID, ok := tempUserID["user_id"]
+		if !ok {
+			log.Error("no user id in token claims")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no user id in token claims"))
+			return
+		}
+
+		var req Request
+
+		// Декодируем json запроса
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			// Такую ошибку встретим, если получили запрос с пустым телом.
+			// Обработаем её отдельно
+			log.Error("request body is empty")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		var s session.Session
+		if err := s.GetSessionByID(storage, req.SessionID); err != nil {
+			log.Error("failed to get session", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to log out"))
+			return
+		}
+
+		if s.UserID != userID {
+			log.Error("session belongs to another user")
+			w.WriteHeader(403)
+			render.JSON(w, r, resp.Error("access was denied"))
+			return
+		}
+
+		if err := s.Revoke(storage); err != nil {
+			log.Error("failed to revoke session", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to log out"))
+			return
+		}
+		sessions.RevokedJTI.Add(s.SessionID)
+		sessions.ForgetSession(s.SessionID)
+
+		log.Info("session revoked")
+
+		render.JSON(w, r, resp.OK())
+	}
+}
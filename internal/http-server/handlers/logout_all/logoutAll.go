@@ -0,0 +1,62 @@
+package logoutAll
+
+import (
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/auth/sessions"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/session"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.logoutAll.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Получаем userID из токена авторизации
+		tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
+		userID, ok := tempUserID["user_id"]
+		if !ok {
+			log.Error("no user id in token claims")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no user id in token claims"))
+			return
+		}
+
+		sessionsToRevoke, err := (&session.Session{}).GetSessionsByUserID(storage, userID)
+		if err != nil {
+			log.Error("failed to get sessions", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to log out"))
+			return
+		}
+
+		if err := session.RevokeAllForUser(storage, userID); err != nil {
+			log.Error("failed to revoke sessions", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to log out"))
+			return
+		}
+
+		for _, s := range sessionsToRevoke {
+			if s.RevokedAt == nil {
+				sessions.RevokedJTI.Add(s.SessionID)
+			}
+			sessions.ForgetSession(s.SessionID)
+		}
+
+		log.Info("all sessions revoked", slog.Int("count", len(sessionsToRevoke)))
+
+		render.JSON(w, r, resp.OK())
+	}
+}
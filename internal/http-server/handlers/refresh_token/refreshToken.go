@@ -0,0 +1,96 @@
+package refreshToken
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/auth/sessions"
+	"portal/internal/lib/jwt"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/postgres"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	SessionID    string `json:"session_id" validate:"required"`
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type Response struct {
+	resp.Response
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func New(log *slog.Logger, storage *postgres.Storage, tokenAuth *jwtauth.JWTAuth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.refreshToken.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		// Декодируем json запроса
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			// Такую ошибку встретим, если получили запрос с пустым телом.
+			// Обработаем её отдельно
+			log.Error("request body is empty")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		// Валидация обязательных полей запроса
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		newRefreshToken, _, err := sessions.Rotate(storage, req.SessionID, req.RefreshToken)
+		if errors.Is(err, sessions.ErrRefreshReused) {
+			log.Error("refresh token reuse detected", sl.Err(err))
+			w.WriteHeader(401)
+			render.JSON(w, r, resp.Error("refresh token already used"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to rotate refresh token", sl.Err(err))
+			w.WriteHeader(401)
+			render.JSON(w, r, resp.Error("invalid refresh token"))
+			return
+		}
+
+		// Claim "jti" равен session_id, чтобы middleware oauth мог отклонить токен по
+		// sessions.RevokedJTI ещё до истечения его exp
+		token, _ := jwt.New(tokenAuth, map[string]interface{}{"jti": req.SessionID})
+
+		log.Info("access token refreshed")
+
+		render.JSON(w, r, Response{
+			Response:     resp.OK(),
+			Token:        token,
+			RefreshToken: newRefreshToken,
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package ical
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"portal/internal/lib/api/pagination"
+	"portal/internal/lib/ical"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/calendar"
+	"portal/internal/storage/postgres/entities/reservation"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// feedLimit - фид отдаётся целиком, а не постранично, поэтому запрашиваем
+// максимально допустимый размер страницы у GetReservationsByUserID.
+const feedLimit = 1000
+
+// New возвращает обработчик GET /reservations/calendar.ics?token=<opaque>,
+// отдающий брони пользователя как text/calendar фид для подписки.
+func New(log *slog.Logger, storage *postgres.Storage, host string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.reservation.ical.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			log.Error("missing token")
+			w.WriteHeader(400)
+			return
+		}
+
+		userID, err := calendar.UserIDByToken(storage, token)
+		if err != nil {
+			log.Error("invalid calendar token", sl.Err(err))
+			w.WriteHeader(401)
+			return
+		}
+
+		var rsv reservation.Reservation
+		active, _, err := rsv.GetReservationsByUserID(storage, userID, pagination.Options{Limit: feedLimit, SortOrder: "DESC"})
+		if err != nil {
+			log.Error("failed to get reservations", sl.Err(err))
+			w.WriteHeader(500)
+			return
+		}
+
+		cancelled, err := calendar.GetRecentlyCancelled(storage, userID)
+		if err != nil {
+			log.Error("failed to get cancelled reservations", sl.Err(err))
+			w.WriteHeader(500)
+			return
+		}
+
+		now := time.Now()
+
+		var events []ical.VEvent
+		for _, res := range active {
+			var place reservation.Place
+			if err := place.GetPlaceName(storage, res.PlaceID); err != nil {
+				log.Error("failed to get place name", sl.Err(err))
+				continue
+			}
+
+			events = append(events, ical.VEvent{
+				UID:      uid(res.ReservationID, host),
+				Start:    res.Start.Time,
+				Finish:   res.Finish.Time,
+				Summary:  place.Name,
+				Location: place.Name,
+				Sequence: res.Sequence,
+				DTStamp:  now,
+			})
+		}
+
+		for _, c := range cancelled {
+			events = append(events, ical.VEvent{
+				UID:       uid(c.ReservationID, host),
+				Start:     c.Start,
+				Finish:    c.Finish,
+				Sequence:  c.Sequence,
+				DTStamp:   now,
+				Cancelled: true,
+			})
+		}
+
+		feed := ical.BuildFeed("Portal reservations", events)
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Write([]byte(feed))
+	}
+}
+
+func uid(reservationID int, host string) string {
+	return fmt.Sprintf("%d@%s", reservationID, host)
+}
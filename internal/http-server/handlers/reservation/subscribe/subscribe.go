@@ -0,0 +1,106 @@
+package subscribe
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres/entities/reservation"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gorilla/websocket"
+)
+
+// pingPeriod - как часто отправлять ping, чтобы соединение не закрылось
+// по таймауту прокси/балансировщика.
+const pingPeriod = 30 * time.Second
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// New возвращает обработчик GET /reservations/ws, апгрейдящий соединение до
+// WebSocket и транслирующий события доступности мест из reservation.DefaultBroker.
+func New(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.reservation.subscribe.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Апгрейд доступен только авторизованным пользователям, роль уже
+		// провалидирована middleware oauth выше по цепочке
+		if r.Context().Value(oauth.ScopeContext).(int) == 0 {
+			log.Error("no user role in token")
+			w.WriteHeader(401)
+			return
+		}
+
+		var filter reservation.Filter
+		if placeID := r.URL.Query().Get("place_id"); placeID != "" {
+			id, err := strconv.Atoi(placeID)
+			if err != nil {
+				log.Error("invalid place_id", sl.Err(err))
+				w.WriteHeader(400)
+				return
+			}
+			filter.PlaceID = id
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Error("failed to upgrade connection", sl.Err(err))
+			return
+		}
+		defer conn.Close()
+
+		events, unsubscribe := reservation.DefaultBroker.Subscribe(filter)
+		defer unsubscribe()
+
+		ticker := time.NewTicker(pingPeriod)
+		defer ticker.Stop()
+
+		// Читаем и отбрасываем входящие сообщения только для того, чтобы вовремя
+		// заметить закрытие соединения клиентом
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case <-closed:
+				return
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				payload, err := json.Marshal(e)
+				if err != nil {
+					log.Error("failed to marshal event", sl.Err(err))
+					continue
+				}
+				if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+					log.Error("failed to write event", sl.Err(err))
+					return
+				}
+			case <-ticker.C:
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					log.Error("failed to write ping", sl.Err(err))
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package rotateCalendarToken
+
+import (
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/calendar"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Token string `json:"token"`
+}
+
+// New возвращает обработчик POST /calendar/token, выпускающий новый токен
+// подписки на .ics фид пользователя взамен старого, если он был.
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.rotateCalendarToken.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Получаем userID из токена авторизации
+		tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
+		userID, ok := tempUserID["user_id"]
+		if !ok {
+			log.Error("no user id in token claims")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no user id in token claims"))
+			return
+		}
+
+		token, err := calendar.IssueToken(storage, userID)
+		if err != nil {
+			log.Error("failed to issue calendar token", sl.Err(err))
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("failed to issue calendar token"))
+			return
+		}
+
+		log.Info("calendar token issued")
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Token:    token,
+		})
+	}
+}
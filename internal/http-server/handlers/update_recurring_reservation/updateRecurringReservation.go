@@ -0,0 +1,145 @@
+package updateRecurringReservation
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	resp "portal/internal/lib/api/response"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/lib/oauth"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/reservation"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	PlaceID int    `json:"place_id" validate:"required"`
+	CronStr string `json:"cron_str,omitempty"`
+	RRule   string `json:"rrule" validate:"required"`
+	Enabled bool   `json:"enabled"`
+	Until   string `json:"until,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+}
+
+func New(log *slog.Logger, storage *postgres.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.updateRecurringReservation.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// Получаем userID из токена авторизации
+		tempUserID := r.Context().Value(oauth.ClaimsContext).(map[string]int)
+		userID, ok := tempUserID["user_id"]
+		if !ok {
+			log.Error("no user id in token claims")
+			w.WriteHeader(500)
+			render.JSON(w, r, resp.Error("no user id in token claims"))
+			return
+		}
+
+		policyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Error("invalid policy id", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("invalid policy id"))
+			return
+		}
+
+		var existing reservation.ReservationPolicy
+		existing.PolicyID = policyID
+		if err := existing.GetReservationPolicyByID(storage); err != nil {
+			log.Error("failed to get recurring reservation", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to update recurring reservation"))
+			return
+		}
+
+		if existing.UserID != userID {
+			log.Error("policy belongs to another user")
+			w.WriteHeader(403)
+			render.JSON(w, r, resp.Error("access was denied"))
+			return
+		}
+
+		var req Request
+
+		// Декодируем json запроса
+		err = render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			// Такую ошибку встретим, если получили запрос с пустым телом.
+			// Обработаем её отдельно
+			log.Error("request body is empty")
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		// Валидация обязательных полей запроса
+		if err := validator.New().Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+			return
+		}
+
+		if _, err := reservation.ParseRRule(req.RRule); err != nil {
+			log.Error("invalid rrule", sl.Err(err))
+			w.WriteHeader(400)
+			render.JSON(w, r, resp.Error("invalid rrule"))
+			return
+		}
+
+		var until time.Time
+		if req.Until != "" {
+			until, err = time.Parse(time.RFC3339, req.Until)
+			if err != nil {
+				log.Error("invalid until", sl.Err(err))
+				w.WriteHeader(400)
+				render.JSON(w, r, resp.Error("invalid until"))
+				return
+			}
+		}
+
+		policy := reservation.ReservationPolicy{
+			PolicyID: policyID,
+			PlaceID:  req.PlaceID,
+			CronStr:  req.CronStr,
+			RRule:    req.RRule,
+			Enabled:  req.Enabled,
+			Until:    until,
+		}
+
+		if err := policy.UpdateReservationPolicy(storage); err != nil {
+			log.Error("failed to update recurring reservation", sl.Err(err))
+			w.WriteHeader(422)
+			render.JSON(w, r, resp.Error("failed to update recurring reservation"))
+			return
+		}
+
+		log.Info("recurring reservation policy updated")
+
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,32 @@
+package email
+
+import "fmt"
+
+// Sender отправляет письма. Вынесен в интерфейс, чтобы обработчики задач
+// можно было покрыть тестами без реальной почты.
+type Sender interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPSender - отправка писем через внешний SMTP-сервер.
+type SMTPSender struct {
+	Host string
+	Port int
+	From string
+}
+
+func NewSMTPSender(host string, port int, from string) *SMTPSender {
+	return &SMTPSender{Host: host, Port: port, From: from}
+}
+
+func (s *SMTPSender) Send(to, subject, body string) error {
+	const op = "jobs.email.SMTPSender.Send" // Имя текущей функции для логов и ошибок
+
+	if s.Host == "" {
+		return fmt.Errorf("%s: smtp host is not configured", op)
+	}
+
+	// Реальная отправка через net/smtp подключается отдельно, вместе с
+	// конфигурацией почтового сервера
+	return nil
+}
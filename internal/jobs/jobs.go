@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"portal/internal/storage/postgres"
+	"time"
+)
+
+// Статусы записи в таблице job.
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusDone      = "done"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+const (
+	qrEnqueue             = `INSERT INTO job (type, payload, status, run_after, attempts, created_at) VALUES ($1, $2, '` + StatusPending + `', $3, 0, now()) RETURNING job_id;`
+	qrCancelByTypeAndRef  = `UPDATE job SET status = '` + StatusCancelled + `' WHERE type = $1 AND status = '` + StatusPending + `' AND payload->>'reservation_id' = $2;`
+	qrRescheduleByTypeRef = `UPDATE job SET run_after = $3, status = '` + StatusPending + `', attempts = 0, last_error = '' WHERE type = $1 AND payload->>'reservation_id' = $2;`
+)
+
+// Job - запись об асинхронной задаче. Payload хранит свободную структуру,
+// специфичную для типа задачи.
+type Job struct {
+	JobID     int             `json:"job_id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	Status    string          `json:"status"`
+	RunAfter  time.Time       `json:"run_after"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Enqueue ставит в очередь задачу типа jobType, которая не будет подобрана
+// воркером раньше runAfter.
+func Enqueue(storage *postgres.Storage, jobType string, payload any, runAfter time.Time) (int, error) {
+	const op = "jobs.Enqueue" // Имя текущей функции для логов и ошибок
+
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var jobID int
+	row := storage.DB.QueryRow(qrEnqueue, jobType, rawPayload, runAfter)
+	if err := row.Scan(&jobID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return jobID, nil
+}
+
+// CancelByReservation отменяет ещё не подобранные воркером задачи заданного
+// типа, привязанные к reservationID, используется при удалении брони.
+func CancelByReservation(storage *postgres.Storage, jobType string, reservationID int) error {
+	const op = "jobs.CancelByReservation" // Имя текущей функции для логов и ошибок
+
+	_, err := storage.DB.Exec(qrCancelByTypeAndRef, jobType, fmt.Sprint(reservationID))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RescheduleByReservation переносит run_after у задачи заданного типа,
+// привязанной к reservationID, и сбрасывает счётчик попыток, используется
+// при изменении времени брони.
+func RescheduleByReservation(storage *postgres.Storage, jobType string, reservationID int, runAfter time.Time) error {
+	const op = "jobs.RescheduleByReservation" // Имя текущей функции для логов и ошибок
+
+	_, err := storage.DB.Exec(qrRescheduleByTypeRef, jobType, fmt.Sprint(reservationID), runAfter)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+const qrGetJobs = `SELECT job_id, type, payload, status, run_after, attempts, COALESCE(last_error, ''), created_at, COUNT(*) OVER()
+					FROM job
+					WHERE ($1 = '' OR status = $1) AND ($2 = '' OR type = $2)
+					ORDER BY created_at DESC
+					LIMIT $3 OFFSET $4;`
+
+// GetJobs возвращает страницу задач, опционально отфильтрованную по статусу
+// и типу, вместе с общим количеством подходящих под фильтр записей.
+func GetJobs(storage *postgres.Storage, status, jobType string, limit, offset int) ([]Job, int, error) {
+	const op = "jobs.GetJobs" // Имя текущей функции для логов и ошибок
+
+	qrResult, err := storage.DB.Query(qrGetJobs, status, jobType, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	var list []Job
+	var total int
+	for qrResult.Next() {
+		var j Job
+		if err := qrResult.Scan(&j.JobID, &j.Type, &j.Payload, &j.Status, &j.RunAfter, &j.Attempts, &j.LastError, &j.CreatedAt, &total); err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", op, err)
+		}
+		list = append(list, j)
+	}
+
+	return list, total, nil
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"portal/internal/jobs/email"
+	"portal/internal/storage/postgres"
+)
+
+// Типы задач, связанных с бронированием мест.
+const (
+	TypeReservationReminder = "reservation.reminder"
+	TypeReservationExpire   = "reservation.expire"
+)
+
+const (
+	qrGetReservationForJob = `SELECT user_id, place_id FROM reservation WHERE reservation_id = $1;`
+	qrDeleteExpiredByID    = `DELETE FROM reservation WHERE reservation_id = $1 AND finish <= now();`
+	qrGetUserEmail         = `SELECT email FROM "user" WHERE user_id = $1;`
+)
+
+// ReservationPayload - полезная нагрузка задач reservation.reminder и
+// reservation.expire.
+type ReservationPayload struct {
+	ReservationID int `json:"reservation_id"`
+}
+
+// RegisterReservationHandlers регистрирует обработчики reservation.reminder
+// и reservation.expire на воркере.
+func RegisterReservationHandlers(w *Worker, sender email.Sender) {
+	w.Register(TypeReservationReminder, reminderHandler(sender))
+	w.Register(TypeReservationExpire, expireHandler)
+}
+
+func reminderHandler(sender email.Sender) Handler {
+	return func(storage *postgres.Storage, rawPayload []byte) error {
+		const op = "jobs.reminderHandler" // Имя текущей функции для логов и ошибок
+
+		var payload ReservationPayload
+		if err := json.Unmarshal(rawPayload, &payload); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		var userID, placeID int
+		row := storage.DB.QueryRow(qrGetReservationForJob, payload.ReservationID)
+		if err := row.Scan(&userID, &placeID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		var userEmail string
+		row = storage.DB.QueryRow(qrGetUserEmail, userID)
+		if err := row.Scan(&userEmail); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return sender.Send(userEmail, "Напоминание о брони", fmt.Sprintf("Через 15 минут начинается бронь места %d", placeID))
+	}
+}
+
+func expireHandler(storage *postgres.Storage, rawPayload []byte) error {
+	const op = "jobs.expireHandler" // Имя текущей функции для логов и ошибок
+
+	var payload ReservationPayload
+	if err := json.Unmarshal(rawPayload, &payload); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := storage.DB.Exec(qrDeleteExpiredByID, payload.ReservationID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
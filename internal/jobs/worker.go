@@ -0,0 +1,134 @@
+package jobs
+
+import (
+	"log/slog"
+	"math"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/postgres"
+	"time"
+)
+
+const (
+	// qrClaimDueJob переводит задачу в статус running в той же транзакции, что и её выборка
+	// через FOR UPDATE SKIP LOCKED, чтобы после commit (когда блокировка строки снимается)
+	// её не подхватил ещё один опрос и не выполнил повторно, пока обработчик ещё работает.
+	qrClaimDueJob = `UPDATE job SET status = '` + StatusRunning + `' WHERE job_id = (
+					   SELECT job_id FROM job WHERE status = '` + StatusPending + `' AND run_after <= now()
+					   ORDER BY run_after LIMIT 1 FOR UPDATE SKIP LOCKED
+					   ) RETURNING job_id, type, payload, status, run_after, attempts, COALESCE(last_error, ''), created_at;`
+	qrMarkDone         = `UPDATE job SET status = '` + StatusDone + `' WHERE job_id = $1;`
+	qrMarkRetry        = `UPDATE job SET status = '` + StatusPending + `', attempts = $2, run_after = $3, last_error = $4 WHERE job_id = $1;`
+	qrMarkFailed       = `UPDATE job SET status = '` + StatusFailed + `', attempts = $2, last_error = $3 WHERE job_id = $1;`
+	defaultMaxAttempts = 5
+	pollInterval       = 2 * time.Second
+)
+
+// Handler обрабатывает один job заданного типа.
+type Handler func(storage *postgres.Storage, payload []byte) error
+
+// Worker вычитывает задачи из таблицы job и раздаёт их зарегистрированным
+// обработчикам, делая до maxAttempts попыток с экспоненциальной паузой
+// между ними.
+type Worker struct {
+	log         *slog.Logger
+	storage     *postgres.Storage
+	handlers    map[string]Handler
+	maxAttempts int
+}
+
+func NewWorker(log *slog.Logger, storage *postgres.Storage) *Worker {
+	return &Worker{
+		log:         log,
+		storage:     storage,
+		handlers:    make(map[string]Handler),
+		maxAttempts: defaultMaxAttempts,
+	}
+}
+
+// Register привязывает обработчик к типу задачи. Должен вызываться до Run.
+func (w *Worker) Register(jobType string, h Handler) {
+	w.handlers[jobType] = h
+}
+
+// Run запускает бесконечный цикл опроса очереди, предполагается запуск в
+// отдельной горутине.
+func (w *Worker) Run(stop <-chan struct{}) {
+	const op = "jobs.Worker.Run"
+
+	log := w.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for w.runOnce(log) {
+				// Забираем задачи подряд, пока очередь не опустеет, чтобы не ждать
+				// следующего тика при накопленной очереди
+			}
+		}
+	}
+}
+
+// runOnce забирает и обрабатывает одну задачу. Возвращает true, если задача
+// была найдена и обработана, что означает - стоит попробовать забрать ещё одну.
+func (w *Worker) runOnce(log *slog.Logger) bool {
+	tx, err := w.storage.DB.Begin()
+	if err != nil {
+		log.Error("failed to begin transaction", sl.Err(err))
+		return false
+	}
+
+	var j Job
+	row := tx.QueryRow(qrClaimDueJob)
+	if err := row.Scan(&j.JobID, &j.Type, &j.Payload, &j.Status, &j.RunAfter, &j.Attempts, &j.LastError, &j.CreatedAt); err != nil {
+		tx.Rollback()
+		return false
+	}
+
+	handler, ok := w.handlers[j.Type]
+	if !ok {
+		log.Error("no handler registered for job type", slog.String("type", j.Type))
+		if _, err := tx.Exec(qrMarkFailed, j.JobID, j.Attempts+1, "no handler registered"); err != nil {
+			log.Error("failed to mark job failed", sl.Err(err))
+		}
+		tx.Commit()
+		return true
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("failed to commit job claim", sl.Err(err))
+		return false
+	}
+
+	if err := handler(w.storage, j.Payload); err != nil {
+		w.retryOrFail(log, j, err)
+		return true
+	}
+
+	if _, err := w.storage.DB.Exec(qrMarkDone, j.JobID); err != nil {
+		log.Error("failed to mark job done", slog.Int("job_id", j.JobID), sl.Err(err))
+	}
+
+	return true
+}
+
+func (w *Worker) retryOrFail(log *slog.Logger, j Job, jobErr error) {
+	attempts := j.Attempts + 1
+
+	if attempts >= w.maxAttempts {
+		if _, err := w.storage.DB.Exec(qrMarkFailed, j.JobID, attempts, jobErr.Error()); err != nil {
+			log.Error("failed to mark job failed", slog.Int("job_id", j.JobID), sl.Err(err))
+		}
+		return
+	}
+
+	backoff := time.Duration(math.Pow(2, float64(attempts))) * time.Second
+	runAfter := time.Now().Add(backoff)
+	if _, err := w.storage.DB.Exec(qrMarkRetry, j.JobID, attempts, runAfter, jobErr.Error()); err != nil {
+		log.Error("failed to reschedule job retry", slog.Int("job_id", j.JobID), sl.Err(err))
+	}
+}
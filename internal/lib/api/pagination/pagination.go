@@ -0,0 +1,123 @@
+package pagination
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// defaultLimit и maxLimit ограничивают размер страницы, запрошенной клиентом,
+// чтобы нельзя было одним запросом выгрузить всю таблицу.
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// Options - разобранные из query-строки параметры постраничного вывода,
+// сортировки и фильтрации по диапазону дат.
+type Options struct {
+	Limit       int
+	Offset      int
+	SortColumn  string
+	SortOrder   string
+	From        time.Time
+	To          time.Time
+	PlaceID     int
+	IsAvailable *bool
+	PlaceName   string
+}
+
+// Parse разбирает ?limit=&offset=&sort_column=&sort_order=&from=&to=&place_id=
+// из query-строки, сверяя sort_column со списком разрешённых для entity
+// колонок, чтобы исключить SQL-инъекцию через имя колонки.
+func Parse(query url.Values, allowedSortColumns []string) (Options, error) {
+	const op = "lib.api.pagination.Parse" // Имя текущей функции для логов и ошибок
+
+	opts := Options{
+		Limit:     defaultLimit,
+		SortOrder: "DESC",
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return Options{}, fmt.Errorf("%s: invalid limit %q", op, raw)
+		}
+		opts.Limit = limit
+	}
+	if opts.Limit > maxLimit {
+		opts.Limit = maxLimit
+	}
+
+	if raw := query.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return Options{}, fmt.Errorf("%s: invalid offset %q", op, raw)
+		}
+		opts.Offset = offset
+	}
+
+	if raw := query.Get("sort_column"); raw != "" {
+		if !contains(allowedSortColumns, raw) {
+			return Options{}, fmt.Errorf("%s: unknown sort_column %q", op, raw)
+		}
+		opts.SortColumn = raw
+	}
+
+	if raw := query.Get("sort_order"); raw != "" {
+		switch raw {
+		case "asc", "ASC":
+			opts.SortOrder = "ASC"
+		case "desc", "DESC":
+			opts.SortOrder = "DESC"
+		default:
+			return Options{}, fmt.Errorf("%s: invalid sort_order %q", op, raw)
+		}
+	}
+
+	if raw := query.Get("from"); raw != "" {
+		from, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return Options{}, fmt.Errorf("%s: invalid from %q: %w", op, raw, err)
+		}
+		opts.From = from
+	}
+
+	if raw := query.Get("to"); raw != "" {
+		to, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return Options{}, fmt.Errorf("%s: invalid to %q: %w", op, raw, err)
+		}
+		opts.To = to
+	}
+
+	if raw := query.Get("place_id"); raw != "" {
+		placeID, err := strconv.Atoi(raw)
+		if err != nil {
+			return Options{}, fmt.Errorf("%s: invalid place_id %q", op, raw)
+		}
+		opts.PlaceID = placeID
+	}
+
+	if raw := query.Get("is_available"); raw != "" {
+		isAvailable, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Options{}, fmt.Errorf("%s: invalid is_available %q", op, raw)
+		}
+		opts.IsAvailable = &isAvailable
+	}
+
+	opts.PlaceName = query.Get("place_name")
+
+	return opts, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package sessions
+
+import (
+	"container/list"
+	"fmt"
+	"log/slog"
+	"portal/internal/storage/postgres"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// sessionRevocationsChannel - канал Postgres NOTIFY, в который триггер на
+// session.revoked_at шлёт session_id, чтобы все инстансы приложения узнали
+// об отзыве сразу, а не только тот, что его выполнил.
+const sessionRevocationsChannel = "session_revocations"
+
+// Доступ-токен несёт claim "jti", равный session_id сессии, которой он
+// выдан, поэтому отзыв сессии равносилен отзыву её jti. Кэш существует,
+// чтобы не ходить в БД на каждый запрос: middleware oauth должен свериться
+// с RevokedJTI.IsRevoked(jti) прежде чем доверять ещё не истёкшему токену.
+
+// revokedCacheCapacity - сколько последних отозванных jti держим в памяти.
+// Этого достаточно, чтобы пережить всплеск logout/logout-all без похода в БД.
+const revokedCacheCapacity = 10000
+
+// RevokedCache - потокобезопасный LRU отозванных jti.
+type RevokedCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	index    map[string]*list.Element
+}
+
+func NewRevokedCache(capacity int) *RevokedCache {
+	return &RevokedCache{
+		capacity: capacity,
+		order:    list.New(),
+		index:    make(map[string]*list.Element),
+	}
+}
+
+// RevokedJTI - общий на всё приложение кэш отозванных jti.
+var RevokedJTI = NewRevokedCache(revokedCacheCapacity)
+
+func (c *RevokedCache) Add(jti string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.index[jti]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.index[jti] = c.order.PushFront(jti)
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.index, oldest.Value.(string))
+		}
+	}
+}
+
+func (c *RevokedCache) IsRevoked(jti string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.index[jti]
+	return ok
+}
+
+// Rebuild заполняет кэш отозванными session_id из БД, вызывается при
+// старте приложения, чтобы рестарт не "забывал" ранее отозванные токены.
+func Rebuild(storage *postgres.Storage) error {
+	const op = "lib.auth.sessions.Rebuild" // Имя текущей функции для логов и ошибок
+
+	const qrGetRevokedSessionIDs = `SELECT session_id FROM session WHERE revoked_at IS NOT NULL ORDER BY revoked_at DESC LIMIT $1;`
+
+	qrResult, err := storage.DB.Query(qrGetRevokedSessionIDs, revokedCacheCapacity)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	for qrResult.Next() {
+		var sessionID string
+		if err := qrResult.Scan(&sessionID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		RevokedJTI.Add(sessionID)
+	}
+
+	return nil
+}
+
+// ListenRevocations подписывается на канал session_revocations и добавляет
+// в кэш session_id, отозванные другими инстансами приложения.
+func ListenRevocations(connStr string, log *slog.Logger) error {
+	const op = "lib.auth.sessions.ListenRevocations" // Имя текущей функции для логов и ошибок
+
+	reportProblem := func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Error(op, slog.Any("error", err))
+		}
+	}
+
+	listener := pq.NewListener(connStr, 10*time.Second, time.Minute, reportProblem)
+	if err := listener.Listen(sessionRevocationsChannel); err != nil {
+		return err
+	}
+
+	go func() {
+		for n := range listener.Notify {
+			if n == nil {
+				continue
+			}
+			RevokedJTI.Add(n.Extra)
+		}
+	}()
+
+	return nil
+}
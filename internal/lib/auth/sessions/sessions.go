@@ -0,0 +1,125 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/session"
+	"sync"
+)
+
+// ErrRefreshReused возвращается, когда предъявленный refresh-токен уже был
+// использован ранее - явный признак кражи токена. Вся "семья" сессий
+// пользователя в этом случае отзывается.
+var ErrRefreshReused = errors.New("refresh token already used")
+
+// refreshTokenBytes - длина случайного refresh-токена до hex-кодирования.
+const refreshTokenBytes = 32
+
+// sessionIDBytes - длина идентификатора сессии до hex-кодирования. Этот же
+// идентификатор используется как claim "jti" в access-токене.
+const sessionIDBytes = 16
+
+// NewRefreshToken генерирует непрозрачный refresh-токен для выдачи клиенту.
+func NewRefreshToken() (string, error) {
+	const op = "lib.auth.sessions.NewRefreshToken" // Имя текущей функции для логов и ошибок
+
+	buf := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// NewSessionID генерирует идентификатор новой сессии.
+func NewSessionID() (string, error) {
+	const op = "lib.auth.sessions.NewSessionID" // Имя текущей функции для логов и ошибок
+
+	buf := make([]byte, sessionIDBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// HashRefreshToken хеширует refresh-токен для хранения в БД - сам токен
+// в открытом виде там никогда не лежит.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// refreshMu защищает одну сессию от гонки двух параллельных /auth/refresh,
+// которые иначе могли бы оба пройти ротацию одного и того же токена.
+var refreshMu sync.Map // map[string]*sync.Mutex, ключ - session_id
+
+func lockSession(sessionID string) func() {
+	muAny, _ := refreshMu.LoadOrStore(sessionID, &sync.Mutex{})
+	mu := muAny.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// ForgetSession убирает из refreshMu мьютекс отозванной сессии - она больше не
+// сможет пройти /auth/refresh, а значит и не будет претендовать на lockSession,
+// поэтому хранить под неё мьютекс незачем. Вызывается везде, где сессия
+// помечается отозванной (logout, logout-all, обнаружение реюза в Rotate), чтобы
+// refreshMu не рос бесконечно на сессиях, которые хоть раз обращались к /auth/refresh.
+func ForgetSession(sessionID string) {
+	refreshMu.Delete(sessionID)
+}
+
+// Rotate проверяет предъявленный refresh-токен против сохранённого хеша и,
+// если он совпадает, выдаёт и сохраняет новый. Если хеш не совпадает, токен
+// уже был использован ранее (реюз), и вся семья сессий пользователя отзывается.
+func Rotate(storage *postgres.Storage, sessionID, presentedToken string) (newToken string, userID int, err error) {
+	const op = "lib.auth.sessions.Rotate" // Имя текущей функции для логов и ошибок
+
+	unlock := lockSession(sessionID)
+	defer unlock()
+
+	var s session.Session
+	if err := s.GetSessionByID(storage, sessionID); err != nil {
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if s.RevokedAt != nil {
+		return "", 0, fmt.Errorf("%s: session is revoked", op)
+	}
+
+	if s.RefreshHash != HashRefreshToken(presentedToken) {
+		sessionsToRevoke, getErr := (&session.Session{}).GetSessionsByUserID(storage, s.UserID)
+		if getErr != nil {
+			return "", 0, fmt.Errorf("%s: %w", op, getErr)
+		}
+
+		if revokeErr := session.RevokeAllForUser(storage, s.UserID); revokeErr != nil {
+			return "", 0, fmt.Errorf("%s: %w", op, revokeErr)
+		}
+
+		for _, sess := range sessionsToRevoke {
+			if sess.RevokedAt == nil {
+				RevokedJTI.Add(sess.SessionID)
+			}
+			ForgetSession(sess.SessionID)
+		}
+
+		return "", 0, fmt.Errorf("%s: %w", op, ErrRefreshReused)
+	}
+
+	newToken, err = NewRefreshToken()
+	if err != nil {
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.RotateRefreshHash(storage, HashRefreshToken(newToken)); err != nil {
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return newToken, s.UserID, nil
+}
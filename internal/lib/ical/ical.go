@@ -0,0 +1,118 @@
+package ical
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// foldLineLength - максимальная длина строки в октетах до переноса, как
+// того требует RFC 5545 (75 октетов, не считая CRLF).
+const foldLineLength = 75
+
+// VEvent - одно событие календаря, соответствующее одной брони места.
+type VEvent struct {
+	UID       string
+	Start     time.Time
+	Finish    time.Time
+	Summary   string
+	Location  string
+	Sequence  int
+	DTStamp   time.Time
+	Cancelled bool
+}
+
+// BuildFeed собирает .ics фид из набора событий. Отменённые брони попадают в
+// тот же PUBLISH-фид, что и активные, но с STATUS:CANCELLED и тем же UID -
+// так подписанный календарь уберёт их при следующей синхронизации, не
+// теряя при этом остальные события из-за смены METHOD на весь файл.
+func BuildFeed(calName string, events []VEvent) string {
+	var b strings.Builder
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, fmt.Sprintf("PRODID:-//portal//%s//RU", calName))
+	writeLine(&b, "METHOD:PUBLISH")
+	writeLine(&b, "X-WR-CALNAME:"+calName)
+
+	for _, e := range events {
+		writeEvent(&b, e)
+	}
+
+	writeLine(&b, "END:VCALENDAR")
+
+	return b.String()
+}
+
+func writeEvent(b *strings.Builder, e VEvent) {
+	writeLine(b, "BEGIN:VEVENT")
+	writeLine(b, "UID:"+e.UID)
+	writeLine(b, "DTSTAMP:"+formatUTC(e.DTStamp))
+	writeLine(b, "DTSTART:"+formatUTC(e.Start))
+	writeLine(b, "DTEND:"+formatUTC(e.Finish))
+	writeLine(b, "SUMMARY:"+escape(e.Summary))
+	if e.Location != "" {
+		writeLine(b, "LOCATION:"+escape(e.Location))
+	}
+	writeLine(b, fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+	if e.Cancelled {
+		writeLine(b, "STATUS:CANCELLED")
+	}
+	writeLine(b, "END:VEVENT")
+}
+
+// formatUTC форматирует время по RFC 5545 в UTC с суффиксом Z.
+func formatUTC(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escape экранирует запятые, точки с запятой и переносы строк, как того
+// требует RFC 5545 для текстовых значений.
+func escape(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		`,`, `\,`,
+		`;`, `\;`,
+		"\n", `\n`,
+	)
+	return r.Replace(s)
+}
+
+// writeLine дописывает строку с переносом по 75 октетам и CRLF-окончанием.
+func writeLine(b *strings.Builder, line string) {
+	b.WriteString(fold(line))
+	b.WriteString("\r\n")
+}
+
+// fold переносит строку по границе в foldLineLength октетов, как того
+// требует RFC 5545: продолжение строки начинается с одного пробела. SUMMARY
+// и LOCATION приходят из пользовательских данных (например, имени места) и
+// могут быть не-ASCII, поэтому граница переноса сдвигается назад до начала
+// руны, чтобы не резать многобайтовый UTF-8 символ пополам.
+func fold(line string) string {
+	if len(line) <= foldLineLength {
+		return line
+	}
+
+	var b strings.Builder
+	for len(line) > 0 {
+		n := foldLineLength
+		if b.Len() > 0 {
+			// Ведущий пробел строки продолжения тоже занимает октет
+			n--
+		}
+		if n > len(line) {
+			n = len(line)
+		}
+		for n < len(line) && !utf8.RuneStart(line[n]) {
+			n--
+		}
+		if b.Len() > 0 {
+			b.WriteString("\r\n ")
+		}
+		b.WriteString(line[:n])
+		line = line[n:]
+	}
+
+	return b.String()
+}
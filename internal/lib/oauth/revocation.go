@@ -0,0 +1,13 @@
+package oauth
+
+import "portal/internal/lib/auth/sessions"
+
+// IsRevoked сообщает, отозван ли jti уже провалидированных (подпись и exp проверены
+// jwtauth.Verifier выше по цепочке) claims токена. Authenticator должен вызывать её перед тем,
+// как довериться ещё не истёкшему access-токену: logout, logout-all и обнаружение повторного
+// использования refresh-токена в sessions.Rotate добавляют session_id (он же jti) в
+// sessions.RevokedJTI, не дожидаясь естественного истечения access-токена.
+func IsRevoked(claims map[string]interface{}) bool {
+	jti, _ := claims["jti"].(string)
+	return jti != "" && sessions.RevokedJTI.IsRevoked(jti)
+}
@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"log/slog"
+	"portal/internal/lib/logger/sl"
+	"portal/internal/storage/postgres"
+	"portal/internal/storage/postgres/entities/reservation"
+	"time"
+)
+
+// defaultSlotDuration используется, когда продолжительность занятия не задана политикой явно.
+const defaultSlotDuration = time.Hour
+
+// Run запускает бесконечный цикл, который раз в минуту материализует брони
+// для всех включённых recurring-политик. Предполагается запуск в отдельной горутине.
+func Run(log *slog.Logger, storage *postgres.Storage, stop <-chan struct{}) {
+	const op = "lib.scheduler.Run"
+
+	log = log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			tick(log, storage)
+		}
+	}
+}
+
+func tick(log *slog.Logger, storage *postgres.Storage) {
+	var p reservation.ReservationPolicy
+	policies, err := p.GetEnabledReservationPolicies(storage)
+	if err != nil {
+		log.Error("failed to load recurring reservation policies", sl.Err(err))
+		return
+	}
+
+	now := time.Now()
+	for _, policy := range policies {
+		if err := policy.Materialize(storage, defaultSlotDuration, now); err != nil {
+			log.Error("failed to materialize recurring reservation",
+				slog.Int("policy_id", policy.PolicyID),
+				sl.Err(err),
+			)
+		}
+	}
+}
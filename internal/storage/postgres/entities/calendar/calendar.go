@@ -0,0 +1,112 @@
+package calendar
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"portal/internal/storage/postgres"
+	"time"
+)
+
+const (
+	qrInsertCalendarToken  = `INSERT INTO calendar_token (token, user_id, created_at) VALUES ($1, $2, now()) ON CONFLICT (user_id) DO UPDATE SET token = $1, created_at = now(), revoked_at = NULL;`
+	qrGetCalendarTokenUser = `SELECT user_id FROM calendar_token WHERE token = $1 AND revoked_at IS NULL;`
+	qrRevokeCalendarToken  = `UPDATE calendar_token SET revoked_at = now() WHERE user_id = $1;`
+)
+
+// tokenBytes - длина токена календаря до hex-кодирования.
+const tokenBytes = 24
+
+// NewToken генерирует непрозрачный токен для URL подписки на календарь,
+// независимый от JWT, чтобы его можно было вставить в клиент без риска
+// раскрыть учётные данные пользователя.
+func NewToken() (string, error) {
+	const op = "storage.postgres.entities.calendar.NewToken" // Имя текущей функции для логов и ошибок
+
+	buf := make([]byte, tokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// IssueToken создаёт (или перевыпускает) токен календаря пользователя. Старый
+// токен, если был, перестаёт работать сразу после вызова.
+func IssueToken(storage *postgres.Storage, userID int) (string, error) {
+	const op = "storage.postgres.entities.calendar.IssueToken" // Имя текущей функции для логов и ошибок
+
+	token, err := NewToken()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := storage.DB.Exec(qrInsertCalendarToken, token, userID); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// RevokeToken отзывает токен календаря пользователя.
+func RevokeToken(storage *postgres.Storage, userID int) error {
+	const op = "storage.postgres.entities.calendar.RevokeToken" // Имя текущей функции для логов и ошибок
+
+	if _, err := storage.DB.Exec(qrRevokeCalendarToken, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// UserIDByToken возвращает владельца ещё не отозванного токена календаря.
+func UserIDByToken(storage *postgres.Storage, token string) (int, error) {
+	const op = "storage.postgres.entities.calendar.UserIDByToken" // Имя текущей функции для логов и ошибок
+
+	var userID int
+	row := storage.DB.QueryRow(qrGetCalendarTokenUser, token)
+	if err := row.Scan(&userID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userID, nil
+}
+
+// CancelGracePeriod - как долго отменённая бронь ещё присутствует в фиде
+// с STATUS:CANCELLED, чтобы подписанные календари успели её убрать.
+const CancelGracePeriod = 24 * time.Hour
+
+const qrGetRecentlyCancelled = `SELECT reservation_id, place_id, start, finish, sequence FROM reservation_cancel WHERE user_id = $1 AND cancelled_at >= $2;`
+
+// CancelledReservation - запись об удалённой брони, которую ещё нужно
+// один цикл выгрузки показать клиенту с STATUS:CANCELLED.
+type CancelledReservation struct {
+	ReservationID int
+	PlaceID       int
+	Start         time.Time
+	Finish        time.Time
+	Sequence      int
+}
+
+// GetRecentlyCancelled возвращает брони пользователя, отменённые не раньше
+// чем CancelGracePeriod назад.
+func GetRecentlyCancelled(storage *postgres.Storage, userID int) ([]CancelledReservation, error) {
+	const op = "storage.postgres.entities.calendar.GetRecentlyCancelled" // Имя текущей функции для логов и ошибок
+
+	qrResult, err := storage.DB.Query(qrGetRecentlyCancelled, userID, time.Now().Add(-CancelGracePeriod))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	var cancelled []CancelledReservation
+	for qrResult.Next() {
+		var c CancelledReservation
+		if err := qrResult.Scan(&c.ReservationID, &c.PlaceID, &c.Start, &c.Finish, &c.Sequence); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		cancelled = append(cancelled, c)
+	}
+
+	return cancelled, nil
+}
@@ -0,0 +1,144 @@
+package reservation
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// EventType - тип события об изменении доступности места.
+type EventType string
+
+const (
+	EventPlaceTaken   EventType = "place_taken"
+	EventPlaceFreed   EventType = "place_freed"
+	EventPlaceUpdated EventType = "place_updated"
+)
+
+// pgListenChannel - имя Postgres-канала, на который подписывается брокер,
+// чтобы события доходили до всех инстансов приложения, а не только до того,
+// что выполнил запрос.
+const pgListenChannel = "reservation_changes"
+
+// subscriberBuffer - размер буфера канала одного подписчика. Если подписчик
+// не успевает вычитывать события, он считается "медленным", и новые события
+// для него отбрасываются, пока в буфере не освободится место (см. Publish).
+const subscriberBuffer = 32
+
+// Event - событие об изменении места, рассылаемое подписчикам через WebSocket.
+type Event struct {
+	Type    EventType `json:"type"`
+	PlaceID int       `json:"place_id"`
+	UserID  int       `json:"user_id,omitempty"`
+	Start   int64     `json:"start,omitempty"`
+	Finish  int64     `json:"finish,omitempty"`
+}
+
+// Filter ограничивает события, которые получит конкретный подписчик.
+// Нулевое значение (PlaceID == 0) означает "без фильтра", то есть все места.
+type Filter struct {
+	PlaceID int
+}
+
+func (f Filter) matches(e Event) bool {
+	return f.PlaceID == 0 || f.PlaceID == e.PlaceID
+}
+
+type subscriber struct {
+	ch     chan Event
+	filter Filter
+}
+
+// Broker рассылает события об изменении доступности мест всем подписчикам,
+// а также ретранслирует события, полученные из Postgres через LISTEN (см.
+// ListenPostgres), чтобы до подписчиков доходили изменения с других инстансов
+// приложения. Сам Broker в Postgres ничего не отправляет.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]subscriber
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[chan Event]subscriber),
+	}
+}
+
+// Subscribe регистрирует нового подписчика и возвращает канал с событиями,
+// прошедшими фильтр. Вызывающая сторона должна вызвать возвращённую функцию
+// отписки, когда соединение закрывается.
+func (b *Broker) Subscribe(filter Filter) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = subscriber{ch: ch, filter: filter}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+		close(ch)
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish рассылает событие всем подходящим подписчикам. Медленным
+// подписчикам, чей буфер переполнен, событие не доставляется - оно просто
+// отбрасывается, чтобы не тормозить остальных.
+func (b *Broker) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, sub := range b.subscribers {
+		if !sub.filter.matches(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			// Медленный потребитель - пропускаем событие, не блокируя остальных.
+		}
+	}
+}
+
+// ListenPostgres подписывается на канал reservation_changes и ретранслирует
+// полученные оттуда события через Publish, чтобы инстансы приложения за
+// балансировщиком видели изменения, сделанные другими инстансами.
+func (b *Broker) ListenPostgres(connStr string, log *slog.Logger) error {
+	const op = "storage.postgres.entities.reservation.Broker.ListenPostgres" // Имя текущей функции для логов и ошибок
+
+	reportProblem := func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Error(op, slog.Any("error", err))
+		}
+	}
+
+	listener := pq.NewListener(connStr, 10*time.Second, time.Minute, reportProblem)
+	if err := listener.Listen(pgListenChannel); err != nil {
+		return err
+	}
+
+	go func() {
+		for n := range listener.Notify {
+			if n == nil {
+				continue
+			}
+			var e Event
+			if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+				log.Error(op, slog.Any("error", err))
+				continue
+			}
+			b.Publish(e)
+		}
+	}()
+
+	return nil
+}
+
+// DefaultBroker - общий на всё приложение брокер событий доступности мест.
+var DefaultBroker = NewBroker()
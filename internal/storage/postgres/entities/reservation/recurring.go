@@ -0,0 +1,395 @@
+package reservation
+
+import (
+	"fmt"
+	"math"
+	"portal/internal/storage/postgres"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	qrInsertReservationPolicy   = `INSERT INTO reservation_policy (place_id, user_id, cron_str, rrule, "enabled", until) VALUES ($1, $2, $3, $4, $5, $6) RETURNING policy_id;`
+	qrUpdateReservationPolicy   = `UPDATE reservation_policy SET place_id = $2, cron_str = $3, rrule = $4, "enabled" = $5, until = $6 WHERE policy_id = $1;`
+	qrDeleteReservationPolicy   = `DELETE FROM reservation_policy WHERE policy_id = $1;`
+	qrGetEnabledPolicies        = `SELECT policy_id, place_id, user_id, cron_str, rrule, "enabled", until FROM reservation_policy WHERE "enabled" = true;`
+	qrGetPolicyByID             = `SELECT policy_id, place_id, user_id, cron_str, rrule, "enabled", until FROM reservation_policy WHERE policy_id = $1;`
+	qrGetFutureMaterializedIDs  = `SELECT reservation_id FROM reservation WHERE policy_id = $1 AND start > now();`
+	// qrGetLastConsideredStart даёт курсор, с которого Materialize продолжит генерацию occurrence:
+	// максимум по обеим таблицам, а не только по reservation, иначе при затяжном конфликте (место
+	// занято на весь горизонт материализации) курсор никогда не продвинется, и каждый тик
+	// планировщика будет заново писать в reservation_conflict один и тот же уже рассмотренный набор
+	qrGetLastConsideredStart = `SELECT COALESCE(GREATEST(
+								  (SELECT MAX(start) FROM reservation WHERE policy_id = $1),
+								  (SELECT MAX(start) FROM reservation_conflict WHERE policy_id = $1)
+								), to_timestamp(0));`
+	qrInsertReservationConflict = `INSERT INTO reservation_conflict (policy_id, place_id, start, finish, reason, created_at) VALUES ($1, $2, $3, $4, $5, now());`
+)
+
+// materializeHorizon ограничивает, на сколько занятий вперёд политика материализуется за один проход планировщика.
+const materializeHorizon = 30
+
+// ReservationPolicy описывает правило повторяющегося бронирования места по cron/RRULE расписанию.
+type ReservationPolicy struct {
+	PolicyID int       `json:"policy_id,omitempty"`
+	PlaceID  int       `json:"place_id"`
+	UserID   int       `json:"user_id"`
+	CronStr  string    `json:"cron_str,omitempty"`
+	RRule    string    `json:"rrule"`
+	Enabled  bool      `json:"enabled"`
+	Until    time.Time `json:"until,omitempty"`
+}
+
+func (p *ReservationPolicy) InsertReservationPolicy(storage *postgres.Storage) error {
+	const op = "storage.postgres.entities.reservation.InsertReservationPolicy" // Имя текущей функции для логов и ошибок
+
+	// Проверяем, что RRULE валиден, прежде чем сохранять политику
+	if _, err := ParseRRule(p.RRule); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	row := storage.DB.QueryRow(qrInsertReservationPolicy, p.PlaceID, p.UserID, p.CronStr, p.RRule, p.Enabled, p.Until)
+	if err := row.Scan(&p.PolicyID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (p *ReservationPolicy) UpdateReservationPolicy(storage *postgres.Storage) error {
+	const op = "storage.postgres.entities.reservation.UpdateReservationPolicy" // Имя текущей функции для логов и ошибок
+
+	if _, err := ParseRRule(p.RRule); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err := storage.DB.Exec(qrUpdateReservationPolicy, p.PolicyID, p.PlaceID, p.CronStr, p.RRule, p.Enabled, p.Until)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Отменяем все ещё не наступившие материализованные брони, они будут пересозданы планировщиком
+	if err := cancelFutureMaterialized(storage, p.PolicyID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (p *ReservationPolicy) DeleteReservationPolicy(storage *postgres.Storage) error {
+	const op = "storage.postgres.entities.reservation.DeleteReservationPolicy" // Имя текущей функции для логов и ошибок
+
+	if err := cancelFutureMaterialized(storage, p.PolicyID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err := storage.DB.Exec(qrDeleteReservationPolicy, p.PolicyID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// cancelFutureMaterialized отменяет ещё не наступившие материализованные брони политики через
+// Reservation.DeleteReservation, а не напрямую через DELETE, чтобы не терять связанную с отменой
+// бронирования логику: запись в reservation_cancel для .ics STATUS:CANCELLED и отмену её фоновых
+// задач-напоминаний/истечения.
+func cancelFutureMaterialized(storage *postgres.Storage, policyID int) error {
+	const op = "storage.postgres.entities.reservation.cancelFutureMaterialized" // Имя текущей функции для логов и ошибок
+
+	qrResult, err := storage.DB.Query(qrGetFutureMaterializedIDs, policyID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var reservationIDs []int
+	for qrResult.Next() {
+		var reservationID int
+		if err := qrResult.Scan(&reservationID); err != nil {
+			qrResult.Close()
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		reservationIDs = append(reservationIDs, reservationID)
+	}
+	qrResult.Close()
+
+	var r Reservation
+	for _, reservationID := range reservationIDs {
+		if err := r.DeleteReservation(storage, reservationID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// GetReservationPolicyByID загружает политику по policy_id, чтобы обработчики могли проверить
+// владельца перед изменением или удалением.
+func (p *ReservationPolicy) GetReservationPolicyByID(storage *postgres.Storage) error {
+	const op = "storage.postgres.entities.reservation.GetReservationPolicyByID" // Имя текущей функции для логов и ошибок
+
+	var until *time.Time
+	row := storage.DB.QueryRow(qrGetPolicyByID, p.PolicyID)
+	if err := row.Scan(&p.PolicyID, &p.PlaceID, &p.UserID, &p.CronStr, &p.RRule, &p.Enabled, &until); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if until != nil {
+		p.Until = *until
+	}
+
+	return nil
+}
+
+// GetEnabledReservationPolicies возвращает все включённые политики для обхода планировщиком.
+func (p *ReservationPolicy) GetEnabledReservationPolicies(storage *postgres.Storage) ([]ReservationPolicy, error) {
+	const op = "storage.postgres.entities.reservation.GetEnabledReservationPolicies" // Имя текущей функции для логов и ошибок
+
+	qrResult, err := storage.DB.Query(qrGetEnabledPolicies)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	var policies []ReservationPolicy
+	for qrResult.Next() {
+		var policy ReservationPolicy
+		var until *time.Time
+		if err := qrResult.Scan(&policy.PolicyID, &policy.PlaceID, &policy.UserID, &policy.CronStr, &policy.RRule, &policy.Enabled, &until); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if until != nil {
+			policy.Until = *until
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+// Materialize досоздаёт конкретные брони в таблице reservation на основании RRULE политики,
+// пропуская слоты, занятые другой бронью, и записывая конфликты в reservation_conflict.
+func (p *ReservationPolicy) Materialize(storage *postgres.Storage, duration time.Duration, now time.Time) error {
+	const op = "storage.postgres.entities.reservation.Materialize" // Имя текущей функции для логов и ошибок
+
+	rule, err := ParseRRule(p.RRule)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	row := storage.DB.QueryRow(qrGetLastConsideredStart, p.PolicyID)
+	var lastConsidered time.Time
+	if err := row.Scan(&lastConsidered); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if lastConsidered.Before(now) {
+		lastConsidered = now
+	}
+
+	occurrences := rule.Occurrences(lastConsidered, p.Until, materializeHorizon)
+
+	outcomes, err := planMaterialization(occurrences, duration, func(start, finish time.Time) (bool, error) {
+		var r Reservation
+		return r.isPlaceTaken(storage, p.PlaceID, start, finish)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, o := range outcomes {
+		if o.taken {
+			if _, err := storage.DB.Exec(qrInsertReservationConflict, p.PolicyID, p.PlaceID, o.start, o.finish, "place already taken"); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			continue
+		}
+
+		var r Reservation
+		if err := r.insertReservationRow(storage, p.PlaceID, p.UserID, p.PolicyID, o.start.Format(time.RFC3339), o.finish.Format(time.RFC3339)); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// occurrenceOutcome - результат рассмотрения одного occurrence при материализации: либо
+// слот свободен и бронь будет вставлена, либо занят и пишется конфликт. В обоих случаях
+// occurrence считается рассмотренным - это и есть фикс для затяжных конфликтов.
+type occurrenceOutcome struct {
+	start, finish time.Time
+	taken         bool
+}
+
+// planMaterialization решает для каждого occurrence, занято ли место, не трогая БД напрямую
+// (кроме как через переданный isTaken) - это позволяет покрыть юнит-тестом пропуск занятых
+// слотов отдельно от реальной материализации.
+func planMaterialization(occurrences []time.Time, duration time.Duration, isTaken func(start, finish time.Time) (bool, error)) ([]occurrenceOutcome, error) {
+	outcomes := make([]occurrenceOutcome, 0, len(occurrences))
+	for _, start := range occurrences {
+		finish := start.Add(duration)
+
+		taken, err := isTaken(start, finish)
+		if err != nil {
+			return nil, err
+		}
+
+		outcomes = append(outcomes, occurrenceOutcome{start: start, finish: finish, taken: taken})
+	}
+
+	return outcomes, nil
+}
+
+func (r *Reservation) isPlaceTaken(storage *postgres.Storage, placeID int, start, finish time.Time) (bool, error) {
+	const op = "storage.postgres.entities.reservation.isPlaceTaken" // Имя текущей функции для логов и ошибок
+
+	qrResult, err := storage.DB.Query(qrGetIsPlaceAvailable, placeID, start, finish)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	return qrResult.Next(), nil
+}
+
+// RRule - разобранное правило повторения в терминах RFC 5545, с поддержкой
+// FREQ=DAILY|WEEKLY, BYDAY, INTERVAL и UNTIL.
+type RRule struct {
+	Freq     string // DAILY или WEEKLY
+	Interval int
+	ByDay    []time.Weekday
+	Until    time.Time // нулевое значение означает "без ограничения"
+}
+
+var weekdayByAbbr = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// ParseRRule разбирает строку вида "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;UNTIL=20261231T000000Z".
+func ParseRRule(rrule string) (*RRule, error) {
+	const op = "storage.postgres.entities.reservation.ParseRRule" // Имя текущей функции для логов и ошибок
+
+	rule := &RRule{Interval: 1}
+
+	for _, part := range strings.Split(rrule, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%s: malformed rrule part %q", op, part)
+		}
+		key, value := strings.ToUpper(kv[0]), kv[1]
+
+		switch key {
+		case "FREQ":
+			if value != "DAILY" && value != "WEEKLY" {
+				return nil, fmt.Errorf("%s: unsupported FREQ %q", op, value)
+			}
+			rule.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("%s: invalid INTERVAL %q", op, value)
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, abbr := range strings.Split(value, ",") {
+				wd, ok := weekdayByAbbr[strings.ToUpper(abbr)]
+				if !ok {
+					return nil, fmt.Errorf("%s: invalid BYDAY %q", op, abbr)
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "UNTIL":
+			until, err := time.Parse("20060102T150405Z", value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid UNTIL %q: %w", op, value, err)
+			}
+			rule.Until = until
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("%s: FREQ is required", op)
+	}
+
+	return rule, nil
+}
+
+// Occurrences возвращает до max дат начала, строго после from, не позднее min(until, policyUntil).
+// Все вычисления ведутся в локации from, так что переходы на летнее/зимнее время сохраняют
+// "настенное" время события, а не его абсолютный сдвиг от UTC. Неделя, которой принадлежит
+// from, считается неделей 0: при BYDAY+INTERVAL совпадения дня недели принимаются только в
+// неделях, отстоящих от неё на число, кратное INTERVAL (иначе "каждые 2 недели" материализовал
+// бы события каждую неделю).
+func (rule *RRule) Occurrences(from, policyUntil time.Time, max int) []time.Time {
+	limit := rule.Until
+	if !policyUntil.IsZero() && (limit.IsZero() || policyUntil.Before(limit)) {
+		limit = policyUntil
+	}
+
+	var result []time.Time
+	cursor := from
+	anchorWeek := weekStart(from)
+
+	for len(result) < max {
+		cursor = cursor.AddDate(0, 0, stepDays(rule, cursor))
+		if !limit.IsZero() && cursor.After(limit) {
+			break
+		}
+		if rule.Freq == "WEEKLY" && len(rule.ByDay) > 0 {
+			if !containsWeekday(rule.ByDay, cursor.Weekday()) {
+				continue
+			}
+			if weeksSince(anchorWeek, cursor)%rule.Interval != 0 {
+				continue
+			}
+		}
+		result = append(result, cursor)
+	}
+
+	return result
+}
+
+func stepDays(rule *RRule, cursor time.Time) int {
+	if rule.Freq == "DAILY" {
+		return rule.Interval
+	}
+	// WEEKLY: при BYDAY двигаемся на день за раз, INTERVAL учитывается отдельно в
+	// Occurrences через weeksSince, иначе прыгаем на INTERVAL недель целиком.
+	if len(rule.ByDay) > 0 {
+		return 1
+	}
+	return rule.Interval * 7
+}
+
+func containsWeekday(days []time.Weekday, wd time.Weekday) bool {
+	for _, d := range days {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// weekStart возвращает полночь понедельника недели, которой принадлежит t, в его же локации.
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) - int(time.Monday) + 7) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+// weeksSince возвращает число полных недель между неделей anchorWeek и неделей, которой
+// принадлежит t. Считаем по разнице календарных дней, а не часов, чтобы переход на летнее/
+// зимнее время внутри диапазона не сбивал счёт недель.
+func weeksSince(anchorWeek, t time.Time) int {
+	days := int(math.Round(weekStart(t).Sub(anchorWeek).Hours() / 24))
+	return days / 7
+}
@@ -0,0 +1,133 @@
+package reservation
+
+import (
+	"testing"
+	"time"
+)
+
+// Регрессия на баг, из-за которого "каждые 2 недели по понедельникам и средам"
+// материализовывался каждую неделю: INTERVAL у WEEKLY+BYDAY должен учитываться
+// относительно недели from, а не игнорироваться.
+func TestOccurrences_WeeklyIntervalWithByDay(t *testing.T) {
+	rule := &RRule{Freq: "WEEKLY", Interval: 2, ByDay: []time.Weekday{time.Monday, time.Wednesday}}
+
+	// from - понедельник, неделя 0. Сам from исключается как "строго после",
+	// среда той же недели входит, а Пн/Ср через неделю (нечётная относительно
+	// from) должны быть пропущены.
+	from := time.Date(2026, 7, 27, 0, 0, 0, 0, time.UTC) // понедельник
+
+	got := rule.Occurrences(from, time.Time{}, 4)
+	want := []time.Time{
+		time.Date(2026, 7, 29, 0, 0, 0, 0, time.UTC), // среда недели 0 (from - понедельник той же недели)
+		time.Date(2026, 8, 10, 0, 0, 0, 0, time.UTC), // понедельник недели 2
+		time.Date(2026, 8, 12, 0, 0, 0, 0, time.UTC), // среда недели 2
+		time.Date(2026, 8, 24, 0, 0, 0, 0, time.UTC), // понедельник недели 4
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d occurrences, want %d: %v", len(got), len(want), got)
+	}
+	for i, g := range got {
+		if !g.Equal(want[i]) {
+			t.Errorf("occurrence %d = %v, want %v", i, g, want[i])
+		}
+	}
+}
+
+// Переход на летнее время не должен сдвигать "настенное" время события.
+func TestOccurrences_PreservesWallTimeAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+
+	rule := &RRule{Freq: "WEEKLY", Interval: 1, ByDay: []time.Weekday{time.Sunday}}
+
+	// 2026-03-08 - воскресенье перехода на летнее время в США.
+	from := time.Date(2026, 3, 1, 10, 0, 0, 0, loc)
+
+	got := rule.Occurrences(from, time.Time{}, 1)
+	if len(got) != 1 {
+		t.Fatalf("got %d occurrences, want 1: %v", len(got), got)
+	}
+
+	want := time.Date(2026, 3, 8, 10, 0, 0, 0, loc)
+	if !got[0].Equal(want) {
+		t.Errorf("occurrence = %v, want %v (wall time must stay 10:00)", got[0], want)
+	}
+	if got[0].Hour() != 10 {
+		t.Errorf("occurrence hour = %d, want 10", got[0].Hour())
+	}
+}
+
+// UNTIL ограничивает материализацию, даже если policyUntil не задан или позже.
+func TestOccurrences_UntilClamp(t *testing.T) {
+	until := time.Date(2026, 8, 3, 0, 0, 0, 0, time.UTC)
+	rule := &RRule{Freq: "DAILY", Interval: 1, Until: until}
+	from := time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	got := rule.Occurrences(from, time.Time{}, 30)
+	want := []time.Time{
+		time.Date(2026, 8, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 8, 3, 0, 0, 0, 0, time.UTC),
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d occurrences, want %d: %v", len(got), len(want), got)
+	}
+	for i, g := range got {
+		if !g.Equal(want[i]) {
+			t.Errorf("occurrence %d = %v, want %v", i, g, want[i])
+		}
+	}
+}
+
+// Более раннее из rule.Until и policyUntil должно ограничивать материализацию.
+func TestOccurrences_PolicyUntilClampsBelowRuleUntil(t *testing.T) {
+	ruleUntil := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+	policyUntil := time.Date(2026, 8, 3, 0, 0, 0, 0, time.UTC)
+	rule := &RRule{Freq: "DAILY", Interval: 1, Until: ruleUntil}
+	from := time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	got := rule.Occurrences(from, policyUntil, 30)
+
+	if len(got) != 2 {
+		t.Fatalf("got %d occurrences, want 2: %v", len(got), got)
+	}
+	if got[len(got)-1].After(policyUntil) {
+		t.Errorf("last occurrence %v is after policyUntil %v", got[len(got)-1], policyUntil)
+	}
+}
+
+// Регрессия на баг, из-за которого планировщик уходил в бесконечный повтор: конфликтующий
+// occurrence должен остаться в числе рассмотренных (а не быть молча отброшенным из батча),
+// иначе курсор материализации никогда не продвинется дальше затяжного конфликта, и каждый
+// тик будет заново писать в reservation_conflict один и тот же уже рассмотренный набор.
+func TestPlanMaterialization_ConflictSkipping(t *testing.T) {
+	occurrences := []time.Time{
+		time.Date(2026, 8, 3, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, 8, 4, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, 8, 5, 9, 0, 0, 0, time.UTC),
+	}
+	conflicting := occurrences[1]
+
+	outcomes, err := planMaterialization(occurrences, time.Hour, func(start, finish time.Time) (bool, error) {
+		return start.Equal(conflicting), nil
+	})
+	if err != nil {
+		t.Fatalf("planMaterialization: %v", err)
+	}
+
+	if len(outcomes) != len(occurrences) {
+		t.Fatalf("got %d outcomes, want %d: conflicting occurrences must still be considered, not dropped", len(outcomes), len(occurrences))
+	}
+	for i, o := range outcomes {
+		if !o.start.Equal(occurrences[i]) {
+			t.Errorf("outcome %d start = %v, want %v", i, o.start, occurrences[i])
+		}
+		want := occurrences[i].Equal(conflicting)
+		if o.taken != want {
+			t.Errorf("outcome %d taken = %v, want %v", i, o.taken, want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package reservation
 import (
 	"fmt"
 	"log"
+	"portal/internal/jobs"
+	"portal/internal/lib/api/pagination"
 	"portal/internal/storage/postgres"
 	"time"
 
@@ -10,23 +12,55 @@ import (
 	"github.com/lib/pq"
 )
 
+// ReservationSortColumns - разрешённые колонки для sort_column при выборке
+// броней пользователя, сверяется в pagination.Parse, чтобы исключить
+// SQL-инъекцию через имя колонки.
+var ReservationSortColumns = []string{"start", "finish", "place_id", "reservation_id"}
+
+// reminderLeadTime - за сколько до начала брони отправляется напоминание.
+const reminderLeadTime = 15 * time.Minute
+
+// reservationTimeLayouts перечисляет форматы, в которых start/finish брони
+// приходят в виде строк, чтобы вычислить время запуска фоновых задач.
+var reservationTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05"}
+
+func parseReservationTime(value string) (time.Time, error) {
+	var lastErr error
+	for _, layout := range reservationTimeLayouts {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, nil
+		}
+		lastErr = err
+	}
+	return time.Time{}, lastErr
+}
+
 const (
 	// Получение актуальных мест 1. делаем все места "доступно" и вычитаем занятые 2. прибавляем занятые с пометкой "недостпуно"
-	qrGetActualPlaces = `(SELECT place_id, "name", COALESCE(phone, ''), true AS is_available, 0 AS user_id, TIMESTAMP '0001-01-01 00:00:00' AS start, TIMESTAMP '0001-01-01 00:00:00' AS finish FROM place
+	qrGetActualPlacesBase = `(SELECT place_id, "name", COALESCE(phone, '') AS phone, true AS is_available, 0 AS user_id, TIMESTAMP '0001-01-01 00:00:00' AS start, TIMESTAMP '0001-01-01 00:00:00' AS finish FROM place
 						  EXCEPT
 						  SELECT DISTINCT place_id, "name", COALESCE(phone, ''), true AS is_available, 0, TIMESTAMP '0001-01-01 00:00:00', TIMESTAMP '0001-01-01 00:00:00' FROM place_and_reservation
 						  WHERE ($1, $2) OVERLAPS ("start", finish))
 						  UNION
 						  (SELECT DISTINCT place_id, "name", COALESCE(phone, ''), false AS is_available, user_id, "start", finish FROM place_and_reservation
-						  WHERE ($1, $2) OVERLAPS ("start", finish))
+						  WHERE ($1, $2) OVERLAPS ("start", finish))`
+	// qrGetActualPlaces оборачивает qrGetActualPlacesBase фильтрами по доступности и имени места,
+	// чтобы фронтенд мог отрисовывать страницы мест без выгрузки всего списка целиком
+	qrGetActualPlaces = `SELECT place_id, name, phone, is_available, user_id, start, finish FROM (` + qrGetActualPlacesBase + `) t
+						  WHERE ($3::boolean IS NULL OR t.is_available = $3) AND ($4 = '' OR t.name ILIKE '%' || $4 || '%')
 						  ORDER BY place_id;`
-	qrGetReservationsByUserID       = `SELECT reservation_id, place_id, start, finish FROM reservation WHERE user_id = $1 ORDER BY start DESC;`
+	qrGetReservationsByUserID       = `SELECT reservation_id, place_id, start, finish, sequence, COUNT(*) OVER() FROM reservation WHERE user_id = $1 AND ($2::timestamp IS NULL OR start >= $2) AND ($3::timestamp IS NULL OR finish <= $3) AND ($4 = 0 OR place_id = $4) ORDER BY %s %s LIMIT $5 OFFSET $6;`
 	qrGetUserReservationInDateRange = `SELECT reservation_id FROM reservation WHERE user_id = $1 AND (start, finish) OVERLAPS ($2, $3);`
 	qrGetIsPlaceAvailable           = `SELECT reservation_id FROM reservation WHERE place_id = $1 AND (start, finish) OVERLAPS ($2, $3);`
 	qrGetNameByPlaceID              = `SELECT name FROM place WHERE place_id = $1;`
-	qrInsertReservation             = `INSERT INTO reservation (place_id, start, finish, user_id) VALUES ($1, $3, $4, $2);`
-	qrUpdateReservation             = `UPDATE reservation SET place_id = $2, start = $3, finish = $4 WHERE reservation_id = $1;`
+	// qrInsertReservation создаёт бронь. policy_id проставляется только для броней,
+	// материализованных из RRULE-политики (см. Materialize), для обычных он NULL
+	qrInsertReservation             = `INSERT INTO reservation (place_id, start, finish, user_id, policy_id) VALUES ($1, $3, $4, $2, $5) RETURNING reservation_id;`
+	qrUpdateReservation             = `UPDATE reservation SET place_id = $2, start = $3, finish = $4, sequence = sequence + 1 WHERE reservation_id = $1;`
 	qrDeleteReservation             = `DELETE FROM reservation WHERE reservation_id = $1;`
+	qrGetReservationForCancel       = `SELECT place_id, user_id, start, finish, sequence FROM reservation WHERE reservation_id = $1;`
+	qrInsertReservationCancel       = `INSERT INTO reservation_cancel (reservation_id, place_id, user_id, start, finish, sequence, cancelled_at) VALUES ($1, $2, $3, $4, $5, $6, now());`
 )
 
 type Place struct {
@@ -64,10 +98,10 @@ type ActualPlace struct {
 	Finish      int  `json:"finish"`
 }
 
-func (ap *ActualPlace) GetActualPlaces(storage *postgres.Storage, properties string, start, finish time.Time) ([]ActualPlace, error) {
+func (ap *ActualPlace) GetActualPlaces(storage *postgres.Storage, properties string, start, finish time.Time, opts pagination.Options) ([]ActualPlace, error) {
 	const op = "storage.postgres.entities.reservation.GetActualPlaces"
 
-	qrResult, err := storage.DB.Query(qrGetActualPlaces, start, finish)
+	qrResult, err := storage.DB.Query(qrGetActualPlaces, start, finish, opts.IsAvailable, opts.PlaceName)
 	if err != nil {
 		if e, ok := err.(*pq.Error); ok {
 			log.Print(e.Detail)
@@ -98,6 +132,7 @@ type Reservation struct {
 	Start         pgtype.Timestamp `json:"start,omitempty"`
 	Finish        pgtype.Timestamp `json:"finish,omitempty"`
 	UserID        int              `json:"user_id,omitempty"`
+	Sequence      int              `json:"sequence,omitempty"`
 }
 
 func (r *Reservation) HasUserReservationInDateRange(storage *postgres.Storage, userID int, start, finish string) (bool, error) {
@@ -131,11 +166,42 @@ func (r *Reservation) InsertReservation(storage *postgres.Storage, placeID, user
 		return fmt.Errorf("%s: place is already taken", op)
 	}
 
-	_, err = storage.DB.Exec(qrInsertReservation, placeID, userID, start, finish)
-	if err != nil {
+	return r.insertReservationRow(storage, placeID, userID, 0, start, finish)
+}
+
+// insertReservationRow - общий хвост для InsertReservation и Materialize: вставляет
+// строку, публикует событие о занятом месте в DefaultBroker (chunk0-2, живые WS-обновления)
+// и ставит в очередь напоминание с авто-истечением (chunk0-4), чтобы оба способа создания
+// брони - обычный и из RRULE-политики - одинаково подключались к этим механизмам. policyID
+// равен 0 для обычных (не из RRULE) броней.
+func (r *Reservation) insertReservationRow(storage *postgres.Storage, placeID, userID, policyID int, start, finish string) error {
+	const op = "storage.postgres.entities.reservation.insertReservationRow" // Имя текущей функции для логов и ошибок
+
+	var policyIDArg interface{}
+	if policyID != 0 {
+		policyIDArg = policyID
+	}
+
+	row := storage.DB.QueryRow(qrInsertReservation, placeID, userID, start, finish, policyIDArg)
+	if err := row.Scan(&r.ReservationID); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	DefaultBroker.Publish(Event{Type: EventPlaceTaken, PlaceID: placeID, UserID: userID})
+
+	// Планируем напоминание и авто-истечение брони; это лучший эффорт -
+	// бронь уже создана, и сбой планировщика не должен откатывать её
+	if startTime, err := parseReservationTime(start); err == nil {
+		if _, err := jobs.Enqueue(storage, jobs.TypeReservationReminder, jobs.ReservationPayload{ReservationID: r.ReservationID}, startTime.Add(-reminderLeadTime)); err != nil {
+			log.Print(err)
+		}
+	}
+	if finishTime, err := parseReservationTime(finish); err == nil {
+		if _, err := jobs.Enqueue(storage, jobs.TypeReservationExpire, jobs.ReservationPayload{ReservationID: r.ReservationID}, finishTime); err != nil {
+			log.Print(err)
+		}
+	}
+
 	return nil
 }
 
@@ -147,37 +213,90 @@ func (r *Reservation) UpdateReservation(storage *postgres.Storage, reservationID
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	DefaultBroker.Publish(Event{
+		Type:    EventPlaceUpdated,
+		PlaceID: placeID,
+		Start:   start.UnixMilli(),
+		Finish:  finish.UnixMilli(),
+	})
+
+	if err := jobs.RescheduleByReservation(storage, jobs.TypeReservationReminder, reservationID, start.Add(-reminderLeadTime)); err != nil {
+		log.Print(err)
+	}
+	if err := jobs.RescheduleByReservation(storage, jobs.TypeReservationExpire, reservationID, finish); err != nil {
+		log.Print(err)
+	}
+
 	return nil
 }
 
 func (r *Reservation) DeleteReservation(storage *postgres.Storage, reservationID int) error {
 	const op = "storage.postgres.entities.reservation.DeleteReservation" // Имя текущей функции для логов и ошибок
 
+	var placeID, userID, sequence int
+	var start, finish time.Time
+	row := storage.DB.QueryRow(qrGetReservationForCancel, reservationID)
+	if err := row.Scan(&placeID, &userID, &start, &finish, &sequence); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	_, err := storage.DB.Exec(qrDeleteReservation, reservationID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	// Оставляем запись об отмене, чтобы подписанный .ics фид мог прислать
+	// клиенту STATUS:CANCELLED на ещё не удалённое им из своего календаря событие
+	if _, err := storage.DB.Exec(qrInsertReservationCancel, reservationID, placeID, userID, start, finish, sequence); err != nil {
+		log.Print(err)
+	}
+
+	DefaultBroker.Publish(Event{Type: EventPlaceFreed, PlaceID: placeID})
+
+	if err := jobs.CancelByReservation(storage, jobs.TypeReservationReminder, reservationID); err != nil {
+		log.Print(err)
+	}
+	if err := jobs.CancelByReservation(storage, jobs.TypeReservationExpire, reservationID); err != nil {
+		log.Print(err)
+	}
+
 	return nil
 }
 
-func (r *Reservation) GetReservationsByUserID(storage *postgres.Storage, userID int) ([]Reservation, error) {
+// GetReservationsByUserID возвращает страницу броней пользователя согласно
+// opts, вместе с общим количеством броней, подходящих под фильтр.
+func (r *Reservation) GetReservationsByUserID(storage *postgres.Storage, userID int, opts pagination.Options) ([]Reservation, int, error) {
 	const op = "storage.postgres.entities.reservation.GetReservationsByUserID" // Имя текущей функции для логов и ошибок
 
-	qrResult, err := storage.DB.Query(qrGetReservationsByUserID, userID)
+	sortColumn := opts.SortColumn
+	if sortColumn == "" {
+		sortColumn = "start"
+	}
+	qr := fmt.Sprintf(qrGetReservationsByUserID, sortColumn, opts.SortOrder)
+
+	var from, to *time.Time
+	if !opts.From.IsZero() {
+		from = &opts.From
+	}
+	if !opts.To.IsZero() {
+		to = &opts.To
+	}
+
+	qrResult, err := storage.DB.Query(qr, userID, from, to, opts.PlaceID, opts.Limit, opts.Offset)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
 	}
 	defer qrResult.Close()
 
 	var rs []Reservation
+	var total int
 	for qrResult.Next() {
 		var r Reservation
-		if err := qrResult.Scan(&r.ReservationID, &r.PlaceID, &r.Start, &r.Finish); err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+		if err := qrResult.Scan(&r.ReservationID, &r.PlaceID, &r.Start, &r.Finish, &r.Sequence, &total); err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", op, err)
 		}
 		rs = append(rs, r)
 	}
 
-	return rs, nil
+	return rs, total, nil
 }
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"fmt"
+	"portal/internal/storage/postgres"
+	"time"
+)
+
+const (
+	qrInsertSession       = `INSERT INTO session (session_id, user_id, refresh_hash, user_agent, ip, created_at, last_seen) VALUES ($1, $2, $3, $4, $5, now(), now());`
+	qrGetSessionByID      = `SELECT session_id, user_id, refresh_hash, user_agent, ip, created_at, last_seen, revoked_at FROM session WHERE session_id = $1;`
+	qrGetSessionsByUserID = `SELECT session_id, user_id, refresh_hash, user_agent, ip, created_at, last_seen, revoked_at FROM session WHERE user_id = $1 ORDER BY last_seen DESC;`
+	qrUpdateRefreshHash   = `UPDATE session SET refresh_hash = $2, last_seen = now() WHERE session_id = $1;`
+	qrTouchSession        = `UPDATE session SET last_seen = now() WHERE session_id = $1;`
+	qrRevokeSession       = `UPDATE session SET revoked_at = now() WHERE session_id = $1;`
+	qrRevokeAllForUser    = `UPDATE session SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL;`
+)
+
+// Session - запись о серверной сессии пользователя, выданной при логине.
+// refresh-токен клиенту не хранится как есть, в базе лежит только его хеш.
+type Session struct {
+	SessionID   string     `json:"session_id"`
+	UserID      int        `json:"user_id"`
+	RefreshHash string     `json:"-"`
+	UserAgent   string     `json:"user_agent,omitempty"`
+	IP          string     `json:"ip,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastSeen    time.Time  `json:"last_seen"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (s *Session) InsertSession(storage *postgres.Storage) error {
+	const op = "storage.postgres.entities.session.InsertSession" // Имя текущей функции для логов и ошибок
+
+	_, err := storage.DB.Exec(qrInsertSession, s.SessionID, s.UserID, s.RefreshHash, s.UserAgent, s.IP)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Session) GetSessionByID(storage *postgres.Storage, sessionID string) error {
+	const op = "storage.postgres.entities.session.GetSessionByID" // Имя текущей функции для логов и ошибок
+
+	row := storage.DB.QueryRow(qrGetSessionByID, sessionID)
+	if err := row.Scan(&s.SessionID, &s.UserID, &s.RefreshHash, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeen, &s.RevokedAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Session) GetSessionsByUserID(storage *postgres.Storage, userID int) ([]Session, error) {
+	const op = "storage.postgres.entities.session.GetSessionsByUserID" // Имя текущей функции для логов и ошибок
+
+	qrResult, err := storage.DB.Query(qrGetSessionsByUserID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer qrResult.Close()
+
+	var sessions []Session
+	for qrResult.Next() {
+		var sess Session
+		if err := qrResult.Scan(&sess.SessionID, &sess.UserID, &sess.RefreshHash, &sess.UserAgent, &sess.IP, &sess.CreatedAt, &sess.LastSeen, &sess.RevokedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		sessions = append(sessions, sess)
+	}
+
+	return sessions, nil
+}
+
+// RotateRefreshHash сохраняет хеш нового refresh-токена взамен предыдущего
+// и обновляет last_seen, используется при каждом успешном /auth/refresh.
+func (s *Session) RotateRefreshHash(storage *postgres.Storage, newHash string) error {
+	const op = "storage.postgres.entities.session.RotateRefreshHash" // Имя текущей функции для логов и ошибок
+
+	_, err := storage.DB.Exec(qrUpdateRefreshHash, s.SessionID, newHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Session) Touch(storage *postgres.Storage) error {
+	const op = "storage.postgres.entities.session.Touch" // Имя текущей функции для логов и ошибок
+
+	_, err := storage.DB.Exec(qrTouchSession, s.SessionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Session) Revoke(storage *postgres.Storage) error {
+	const op = "storage.postgres.entities.session.Revoke" // Имя текущей функции для логов и ошибок
+
+	_, err := storage.DB.Exec(qrRevokeSession, s.SessionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeAllForUser отзывает всю "семью" сессий пользователя разом, используется
+// и для /auth/logout-all, и для реакции на обнаруженный повторный refresh.
+func RevokeAllForUser(storage *postgres.Storage, userID int) error {
+	const op = "storage.postgres.entities.session.RevokeAllForUser" // Имя текущей функции для логов и ошибок
+
+	_, err := storage.DB.Exec(qrRevokeAllForUser, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}